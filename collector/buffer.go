@@ -0,0 +1,220 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/gom/fluentd_exporter/config"
+)
+
+func init() {
+	registerCollector("buffer", true, newBufferCollector)
+	kingpin.Flag("collector.buffer.path", "Glob of a fluentd file buffer directory (e.g. /var/log/fluentd/buffer/*), optionally prefixed with 'conf_name=' (repeatable).").SetValue(&bufferPaths)
+}
+
+var (
+	bufferPaths       bufferPathFlag
+	bufferWalkTimeout = kingpin.Flag("collector.buffer.timeout", "Timeout for walking buffer directories, so a stuck mount can't wedge /metrics.").Default("10s").Duration()
+	bufferMaxDepth    = kingpin.Flag("collector.buffer.max-depth", "Maximum directory depth to walk under each buffer path.").Default("2").Int()
+)
+
+// bufferChunkRegex recognizes fluentd file buffer chunk names: a stage
+// chunk being written is suffixed "<id>.b<unique_id>.log", a chunk queued
+// for output is suffixed "<id>.q<unique_id>.log".
+var bufferChunkRegex = regexp.MustCompile(`\.(b|q)[0-9a-f]+\.log$`)
+
+var bufferLabelNames = []string{"conf_name", "path", "state"}
+
+// bufferTarget is one configured buffer directory glob, grouped under the
+// conf_name that its metrics should be labeled with.
+type bufferTarget struct {
+	confName string
+	glob     string
+}
+
+// bufferPathFlag collects repeated -collector.buffer.path flags.
+type bufferPathFlag []bufferTarget
+
+func (f *bufferPathFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, t := range *f {
+		parts[i] = t.confName + "=" + t.glob
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *bufferPathFlag) Set(value string) error {
+	confName, glob := "default", value
+	if i := strings.Index(value, "="); i >= 0 {
+		confName, glob = value[:i], value[i+1:]
+	}
+	*f = append(*f, bufferTarget{confName: confName, glob: glob})
+	return nil
+}
+
+// bufferCollector walks configured fluentd file buffer directories and
+// reports chunk counts, sizes, and the oldest chunk's age, surfacing
+// backpressure that isn't visible from process CPU/RSS alone.
+type bufferCollector struct {
+	target   string
+	logger   log.Logger
+	instance *config.Instance
+
+	bufferFilesDesc         *prometheus.Desc
+	bufferBytesDesc         *prometheus.Desc
+	bufferOldestFileAgeDesc *prometheus.Desc
+}
+
+func newBufferCollector(target string, cfgStore *config.Store, logger log.Logger) (Collector, error) {
+	inst, constLabels := instanceLabels(cfgStore, target)
+	return &bufferCollector{
+		target:   target,
+		logger:   logger,
+		instance: inst,
+		bufferFilesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "buffer_files"),
+			"Number of fluentd file buffer chunks on disk",
+			bufferLabelNames, constLabels,
+		),
+		bufferBytesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "buffer_bytes"),
+			"Total size of fluentd file buffer chunks on disk",
+			bufferLabelNames, constLabels,
+		),
+		bufferOldestFileAgeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "buffer_oldest_file_age_seconds"),
+			"Age in seconds of the oldest fluentd file buffer chunk on disk",
+			[]string{"conf_name", "path"}, constLabels,
+		),
+	}, nil
+}
+
+func (c *bufferCollector) Name() string { return "buffer" }
+
+func (c *bufferCollector) Update(ch chan<- prometheus.Metric) error {
+	type result struct {
+		metrics []prometheus.Metric
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		metrics, err := c.walk()
+		done <- result{metrics: metrics, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		for _, m := range r.metrics {
+			ch <- m
+		}
+		return r.err
+	case <-time.After(*bufferWalkTimeout):
+		return fmt.Errorf("timed out walking buffer directories after %s", *bufferWalkTimeout)
+	}
+}
+
+// walk collects metrics into a local slice rather than sending to ch
+// directly, since Update may time out and abandon this goroutine while
+// it is still running a slow filepath.Walk; sending to ch after Update
+// has returned would race client_golang closing the channel.
+func (c *bufferCollector) walk() ([]prometheus.Metric, error) {
+	var metrics []prometheus.Metric
+	var lastErr error
+	for _, target := range c.resolveTargets() {
+		roots, err := filepath.Glob(target.glob)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		for _, root := range roots {
+			rootMetrics, err := c.walkRoot(target.confName, root)
+			if err != nil {
+				level.Error(c.logger).Log("msg", "failed to walk buffer path", "path", root, "err", err)
+				lastErr = err
+				continue
+			}
+			metrics = append(metrics, rootMetrics...)
+		}
+	}
+	return metrics, lastErr
+}
+
+// resolveTargets merges the flag-configured --collector.buffer.path
+// globs matching c.target with the buffer_path of the --config.file
+// instance matched to c.target, if any.
+func (c *bufferCollector) resolveTargets() []bufferTarget {
+	var targets []bufferTarget
+	for _, t := range bufferPaths {
+		if c.target == "" || t.confName == c.target {
+			targets = append(targets, t)
+		}
+	}
+	if c.instance != nil && c.instance.BufferPath != "" {
+		targets = append(targets, bufferTarget{confName: c.instance.Name, glob: c.instance.BufferPath})
+	}
+	return targets
+}
+
+func (c *bufferCollector) walkRoot(confName, root string) ([]prometheus.Metric, error) {
+	files := map[string]int{"staged": 0, "queued": 0}
+	bytes := map[string]int64{"staged": 0, "queued": 0}
+	var oldest time.Time
+
+	baseDepth := strings.Count(filepath.Clean(root), string(filepath.Separator))
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			depth := strings.Count(filepath.Clean(path), string(filepath.Separator)) - baseDepth
+			if depth > *bufferMaxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		m := bufferChunkRegex.FindStringSubmatch(path)
+		if m == nil {
+			return nil
+		}
+
+		state := "staged"
+		if m[1] == "q" {
+			state = "queued"
+		}
+
+		files[state]++
+		bytes[state] += info.Size()
+		if oldest.IsZero() || info.ModTime().Before(oldest) {
+			oldest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []prometheus.Metric
+	for _, state := range []string{"staged", "queued"} {
+		labels := []string{confName, root, state}
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(c.bufferFilesDesc, prometheus.GaugeValue, float64(files[state]), labels...),
+			prometheus.MustNewConstMetric(c.bufferBytesDesc, prometheus.GaugeValue, float64(bytes[state]), labels...),
+		)
+	}
+
+	if !oldest.IsZero() {
+		metrics = append(metrics, prometheus.MustNewConstMetric(c.bufferOldestFileAgeDesc, prometheus.GaugeValue, time.Since(oldest).Seconds(), confName, root))
+	}
+	return metrics, nil
+}
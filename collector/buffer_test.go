@@ -0,0 +1,55 @@
+package collector
+
+import "testing"
+
+func TestBufferChunkRegex(t *testing.T) {
+	cases := []struct {
+		path      string
+		wantMatch bool
+		wantState string
+	}{
+		{"/var/log/fluentd/buffer/buffer.b5929b7b1234.log", true, "staged"},
+		{"/var/log/fluentd/buffer/buffer.q5929b7b1234.log", true, "queued"},
+		{"/var/log/fluentd/buffer/buffer.b5929b7b1234.log.meta", false, ""},
+		{"/var/log/fluentd/buffer/some-other-file", false, ""},
+	}
+
+	for _, c := range cases {
+		m := bufferChunkRegex.FindStringSubmatch(c.path)
+		if (m != nil) != c.wantMatch {
+			t.Errorf("FindStringSubmatch(%q) matched = %v, want %v", c.path, m != nil, c.wantMatch)
+			continue
+		}
+		if !c.wantMatch {
+			continue
+		}
+
+		state := "staged"
+		if m[1] == "q" {
+			state = "queued"
+		}
+		if state != c.wantState {
+			t.Errorf("path %q: state = %q, want %q", c.path, state, c.wantState)
+		}
+	}
+}
+
+func TestBufferPathFlag(t *testing.T) {
+	var f bufferPathFlag
+	if err := f.Set("myconf=/var/log/fluentd/buffer/*"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := f.Set("/var/log/other/buffer/*"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	if len(f) != 2 {
+		t.Fatalf("len(f) = %d, want 2", len(f))
+	}
+	if f[0].confName != "myconf" || f[0].glob != "/var/log/fluentd/buffer/*" {
+		t.Errorf("f[0] = %+v, want confName=myconf glob=/var/log/fluentd/buffer/*", f[0])
+	}
+	if f[1].confName != "default" || f[1].glob != "/var/log/other/buffer/*" {
+		t.Errorf("f[1] = %+v, want confName=default glob=/var/log/other/buffer/*", f[1])
+	}
+}
@@ -0,0 +1,145 @@
+// Package collector includes all individual collectors to gather and
+// export fluentd metrics, modeled on node_exporter's collector package.
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/gom/fluentd_exporter/config"
+)
+
+const namespace = "fluentd"
+
+var (
+	scrapeDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape_collector", "duration_seconds"),
+		"fluentd_exporter: Duration of a collector scrape.",
+		[]string{"collector"}, nil,
+	)
+	scrapeSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape_collector", "success"),
+		"fluentd_exporter: Whether a collector succeeded.",
+		[]string{"collector"}, nil,
+	)
+)
+
+// Collector gathers a group of related metrics for a single target.
+type Collector interface {
+	// Name identifies the collector, e.g. for the "collector" label on
+	// fluentd_scrape_collector_duration_seconds.
+	Name() string
+	// Update sends the collector's metrics for target to ch. An error
+	// is returned when collection failed in whole or in part; metrics
+	// already sent to ch still stand.
+	Update(ch chan<- prometheus.Metric) error
+}
+
+type factoryFunc func(target string, cfgStore *config.Store, logger log.Logger) (Collector, error)
+
+var (
+	factories        = make(map[string]factoryFunc)
+	collectorEnabled = make(map[string]*bool)
+)
+
+// registerCollector makes a collector available under --collector.<name>
+// (and, via kingpin's automatic negation, --no-collector.<name>), enabled
+// by default according to isDefaultEnabled.
+func registerCollector(name string, isDefaultEnabled bool, factory factoryFunc) {
+	defaultState := "disabled"
+	if isDefaultEnabled {
+		defaultState = "enabled"
+	}
+
+	flagName := "collector." + name
+	flagHelp := fmt.Sprintf("Enable the %s collector (default: %s).", name, defaultState)
+	defaultValue := "false"
+	if isDefaultEnabled {
+		defaultValue = "true"
+	}
+
+	collectorEnabled[name] = kingpin.Flag(flagName, flagHelp).Default(defaultValue).Bool()
+	factories[name] = factory
+}
+
+// FluentdCollector implements prometheus.Collector by fanning out to every
+// enabled Collector for a single target, in parallel, and recording each
+// collector's scrape duration and success.
+type FluentdCollector struct {
+	logger     log.Logger
+	collectors map[string]Collector
+}
+
+// NewFluentdCollector builds the set of enabled collectors scoped to
+// target. An empty target collects everything this exporter process can
+// see; the /probe handler always supplies one. cfgStore, if non-nil, is
+// consulted for a --config.file instance matching target; its Labels are
+// attached as const labels on every metric the target's collectors emit.
+func NewFluentdCollector(target string, cfgStore *config.Store, logger log.Logger) (*FluentdCollector, error) {
+	collectors := make(map[string]Collector)
+	for name, enabled := range collectorEnabled {
+		if !*enabled {
+			continue
+		}
+		c, err := factories[name](target, cfgStore, log.With(logger, "collector", name))
+		if err != nil {
+			return nil, fmt.Errorf("collector %s: %w", name, err)
+		}
+		collectors[name] = c
+	}
+	return &FluentdCollector{logger: logger, collectors: collectors}, nil
+}
+
+// instanceLabels looks up target in cfgStore's current config snapshot,
+// returning the matched instance (nil if none) and its Labels converted
+// to prometheus.Labels (nil if the instance has none configured).
+func instanceLabels(cfgStore *config.Store, target string) (*config.Instance, prometheus.Labels) {
+	inst, ok := cfgStore.Get().Instance(target)
+	if !ok || len(inst.Labels) == 0 {
+		return inst, nil
+	}
+	labels := make(prometheus.Labels, len(inst.Labels))
+	for k, v := range inst.Labels {
+		labels[k] = v
+	}
+	return inst, labels
+}
+
+func (f *FluentdCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- scrapeDurationDesc
+	ch <- scrapeSuccessDesc
+}
+
+func (f *FluentdCollector) Collect(ch chan<- prometheus.Metric) {
+	wg := sync.WaitGroup{}
+	wg.Add(len(f.collectors))
+	for _, c := range f.collectors {
+		go func(c Collector) {
+			defer wg.Done()
+			execute(c, ch, f.logger)
+		}(c)
+	}
+	wg.Wait()
+}
+
+func execute(c Collector, ch chan<- prometheus.Metric, logger log.Logger) {
+	begin := time.Now()
+	err := c.Update(ch)
+	duration := time.Since(begin)
+
+	var success float64
+	if err != nil {
+		level.Error(logger).Log("msg", "collector failed", "collector", c.Name(), "duration_seconds", duration.Seconds(), "err", err)
+	} else {
+		level.Debug(logger).Log("msg", "collector succeeded", "collector", c.Name(), "duration_seconds", duration.Seconds())
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), c.Name())
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, c.Name())
+}
@@ -0,0 +1,235 @@
+package collector
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/gom/fluentd_exporter/config"
+)
+
+var (
+	monitorAgentURLs               monitorAgentURLFlag
+	monitorAgentTimeout            = kingpin.Flag("fluentd.monitor-agent-timeout", "Timeout for scraping the monitor_agent API.").Default("5s").Duration()
+	monitorAgentInsecureSkipVerify = kingpin.Flag("fluentd.monitor-agent-tls-insecure-skip-verify", "Disable TLS certificate verification when scraping the monitor_agent API.").Default("false").Bool()
+	monitorAgentUsername           = kingpin.Flag("fluentd.monitor-agent-username", "Username for basic auth against the monitor_agent API.").Default("").String()
+	monitorAgentPassword           = kingpin.Flag("fluentd.monitor-agent-password", "Password for basic auth against the monitor_agent API.").Default("").String()
+)
+
+func init() {
+	registerCollector("monitoragent", true, newMonitorAgentCollector)
+	kingpin.Flag("fluentd.monitor-agent-url", "URL of a fluentd monitor_agent plugins.json endpoint, optionally prefixed with 'conf_name=' (repeatable).").SetValue(&monitorAgentURLs)
+}
+
+// monitorAgentLabelNames labels the metrics scraped from fluentd's
+// monitor_agent plugins.json endpoint.
+var monitorAgentLabelNames = []string{"conf_name", "plugin_id", "plugin_category", "type"}
+
+// monitorAgentTarget is one configured monitor_agent endpoint, grouped under
+// the conf_name that its metrics should be labeled with.
+type monitorAgentTarget struct {
+	confName string
+	url      string
+}
+
+// monitorAgentURLFlag collects repeated -fluentd.monitor-agent-url flags.
+type monitorAgentURLFlag []monitorAgentTarget
+
+func (f *monitorAgentURLFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, t := range *f {
+		parts[i] = t.confName + "=" + t.url
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *monitorAgentURLFlag) Set(value string) error {
+	confName, url := "default", value
+	if i := strings.Index(value, "="); i >= 0 {
+		confName, url = value[:i], value[i+1:]
+	}
+	*f = append(*f, monitorAgentTarget{confName: confName, url: url})
+	return nil
+}
+
+// monitorAgentTargetMatches reports whether target (as given to /probe, or
+// an empty process-wide scrape) selects t, either by its conf_name label
+// or by the host:port of its URL.
+func monitorAgentTargetMatches(t monitorAgentTarget, target string) bool {
+	if target == "" || t.confName == target {
+		return true
+	}
+	if u, err := url.Parse(t.url); err == nil && u.Host == target {
+		return true
+	}
+	return false
+}
+
+// monitorAgentPlugin is a single entry of fluentd's monitor_agent
+// /api/plugins.json response. Only the fields this exporter cares about
+// are decoded; buffer/retry/rollback counters are only populated by
+// fluentd for buffered output plugins.
+type monitorAgentPlugin struct {
+	PluginID                         string  `json:"plugin_id"`
+	PluginCategory                   string  `json:"plugin_category"`
+	Type                             string  `json:"type"`
+	OutputPlugin                     bool    `json:"output_plugin"`
+	BufferQueueLength                float64 `json:"buffer_queue_length"`
+	BufferTotalQueuedSize            float64 `json:"buffer_total_queued_size"`
+	BufferAvailableBufferSpaceRatios float64 `json:"buffer_available_buffer_space_ratios"`
+	RetryCount                       float64 `json:"retry_count"`
+	EmitRecords                      float64 `json:"emit_records"`
+	RollbackCount                    float64 `json:"rollback_count"`
+}
+
+type monitorAgentResponse struct {
+	Plugins []monitorAgentPlugin `json:"plugins"`
+}
+
+func newMonitorAgentHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: *monitorAgentTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: *monitorAgentInsecureSkipVerify},
+		},
+	}
+}
+
+// monitorAgentCollector scrapes fluentd's built-in monitor_agent HTTP API
+// for per-plugin buffer and emit/retry/rollback counters.
+type monitorAgentCollector struct {
+	target   string
+	client   *http.Client
+	logger   log.Logger
+	instance *config.Instance
+
+	bufferQueueLengthDesc                *prometheus.Desc
+	bufferTotalBytesDesc                 *prometheus.Desc
+	bufferAvailableBufferSpaceRatiosDesc *prometheus.Desc
+	outputEmitRecordsTotalDesc           *prometheus.Desc
+	outputRetryCountDesc                 *prometheus.Desc
+	outputRollbackTotalDesc              *prometheus.Desc
+}
+
+func newMonitorAgentCollector(target string, cfgStore *config.Store, logger log.Logger) (Collector, error) {
+	inst, constLabels := instanceLabels(cfgStore, target)
+	return &monitorAgentCollector{
+		target:   target,
+		client:   newMonitorAgentHTTPClient(),
+		logger:   logger,
+		instance: inst,
+		bufferQueueLengthDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "buffer_queue_length"),
+			"fluentd output plugin buffer queue length",
+			monitorAgentLabelNames, constLabels,
+		),
+		bufferTotalBytesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "buffer_total_bytes"),
+			"fluentd output plugin total buffer size in bytes",
+			monitorAgentLabelNames, constLabels,
+		),
+		bufferAvailableBufferSpaceRatiosDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "buffer_available_buffer_space_ratios"),
+			"fluentd output plugin available buffer space ratio",
+			monitorAgentLabelNames, constLabels,
+		),
+		outputEmitRecordsTotalDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "output_emit_records_total"),
+			"fluentd output plugin emitted record count",
+			monitorAgentLabelNames, constLabels,
+		),
+		outputRetryCountDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "output_retry_count"),
+			"fluentd output plugin retry count; not monotonic, resets to 0 after a successful flush",
+			monitorAgentLabelNames, constLabels,
+		),
+		outputRollbackTotalDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "output_rollback_total"),
+			"fluentd output plugin rollback count",
+			monitorAgentLabelNames, constLabels,
+		),
+	}, nil
+}
+
+func (c *monitorAgentCollector) Name() string { return "monitoragent" }
+
+func (c *monitorAgentCollector) Update(ch chan<- prometheus.Metric) error {
+	var lastErr error
+	for _, target := range c.resolveTargets() {
+		if err := c.scrape(target, ch); err != nil {
+			level.Error(c.logger).Log("msg", "failed to scrape monitor_agent", "url", target.url, "err", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// resolveTargets merges the flag-configured --fluentd.monitor-agent-url
+// targets matching c.target with the monitor_agent_url of the
+// --config.file instance matched to c.target, if any.
+func (c *monitorAgentCollector) resolveTargets() []monitorAgentTarget {
+	var targets []monitorAgentTarget
+	for _, t := range monitorAgentURLs {
+		if monitorAgentTargetMatches(t, c.target) {
+			targets = append(targets, t)
+		}
+	}
+	if c.instance != nil && c.instance.MonitorAgentURL != "" {
+		targets = append(targets, monitorAgentTarget{confName: c.instance.Name, url: c.instance.MonitorAgentURL})
+	}
+	return targets
+}
+
+func (c *monitorAgentCollector) scrape(target monitorAgentTarget, ch chan<- prometheus.Metric) error {
+	req, err := http.NewRequest(http.MethodGet, target.url, nil)
+	if err != nil {
+		return err
+	}
+	if *monitorAgentUsername != "" {
+		req.SetBasicAuth(*monitorAgentUsername, *monitorAgentPassword)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errStatusCode(resp.StatusCode)
+	}
+
+	var result monitorAgentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	for _, plugin := range result.Plugins {
+		if !plugin.OutputPlugin {
+			continue
+		}
+
+		labels := []string{target.confName, plugin.PluginID, plugin.PluginCategory, plugin.Type}
+		ch <- prometheus.MustNewConstMetric(c.bufferQueueLengthDesc, prometheus.GaugeValue, plugin.BufferQueueLength, labels...)
+		ch <- prometheus.MustNewConstMetric(c.bufferTotalBytesDesc, prometheus.GaugeValue, plugin.BufferTotalQueuedSize, labels...)
+		ch <- prometheus.MustNewConstMetric(c.bufferAvailableBufferSpaceRatiosDesc, prometheus.GaugeValue, plugin.BufferAvailableBufferSpaceRatios, labels...)
+		ch <- prometheus.MustNewConstMetric(c.outputEmitRecordsTotalDesc, prometheus.CounterValue, plugin.EmitRecords, labels...)
+		ch <- prometheus.MustNewConstMetric(c.outputRetryCountDesc, prometheus.GaugeValue, plugin.RetryCount, labels...)
+		ch <- prometheus.MustNewConstMetric(c.outputRollbackTotalDesc, prometheus.CounterValue, plugin.RollbackCount, labels...)
+	}
+	return nil
+}
+
+type errStatusCode int
+
+func (e errStatusCode) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d", int(e))
+}
@@ -0,0 +1,200 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+
+	"github.com/gom/fluentd_exporter/config"
+)
+
+func init() {
+	registerCollector("proc", true, newProcCollector)
+}
+
+var (
+	// processNameRegex and configFileNameRegex are the legacy process
+	// matcher used when no --config.file instance matches target: any
+	// "/fluentd" process is claimed, grouped by the conf_name parsed out
+	// of its "-c"/"--config" command-line flag (or "default" if absent).
+	processNameRegex    = regexp.MustCompile(`/fluentd\s*`)
+	configFileNameRegex = regexp.MustCompile(`\s(-c|--config)\s.*/(.+)\.conf\s*`)
+
+	procLabelNames = []string{"conf_name", "worker_number", "pid"}
+)
+
+// procCollector reports per-process CPU/memory usage for fluentd worker
+// processes found in procfs, grouped by conf_name.
+type procCollector struct {
+	target   string
+	fs       procfs.FS
+	logger   log.Logger
+	instance *config.Instance
+
+	cpuTimeDesc        *prometheus.Desc
+	virtualMemoryDesc  *prometheus.Desc
+	residentMemoryDesc *prometheus.Desc
+	fluentdUpDesc      *prometheus.Desc
+}
+
+func newProcCollector(target string, cfgStore *config.Store, logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(procfs.DefaultMountPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	inst, constLabels := instanceLabels(cfgStore, target)
+	return &procCollector{
+		target:   target,
+		fs:       fs,
+		logger:   logger,
+		instance: inst,
+		cpuTimeDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "cpi_time"),
+			"fluentd cpu time",
+			procLabelNames, constLabels,
+		),
+		virtualMemoryDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "virtual_memory_usage"),
+			"fluentd virtual memory usage",
+			procLabelNames, constLabels,
+		),
+		residentMemoryDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "resident_memory_usage"),
+			"fluentd resident memory usage",
+			procLabelNames, constLabels,
+		),
+		fluentdUpDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "up"),
+			"the fluentd processes",
+			nil, constLabels,
+		),
+	}, nil
+}
+
+func (c *procCollector) Name() string { return "proc" }
+
+func (c *procCollector) Update(ch chan<- prometheus.Metric) error {
+	ids, err := c.resolveFluentdIds()
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.fluentdUpDesc, prometheus.GaugeValue, 0)
+		return err
+	}
+
+	level.Debug(c.logger).Log("msg", "resolved fluentd ids", "ids", fmt.Sprintf("%v", ids))
+
+	var lastErr error
+	workers := 0
+	for groupKey, pidList := range ids {
+		for i, pid := range pidList {
+			procStat, err := c.procStat(pid)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			labels := []string{groupKey, strconv.Itoa(i), strconv.Itoa(pid)}
+			ch <- prometheus.MustNewConstMetric(c.cpuTimeDesc, prometheus.GaugeValue, procStat.CPUTime(), labels...)
+			ch <- prometheus.MustNewConstMetric(c.virtualMemoryDesc, prometheus.GaugeValue, float64(procStat.VirtualMemory()), labels...)
+			ch <- prometheus.MustNewConstMetric(c.residentMemoryDesc, prometheus.GaugeValue, float64(procStat.ResidentMemory()), labels...)
+
+			workers++
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.fluentdUpDesc, prometheus.GaugeValue, float64(workers))
+	return lastErr
+}
+
+func (c *procCollector) resolveFluentdIds() (map[string][]int, error) {
+	ids := make(map[string][]int)
+	// map[conf_name] = list of pid (workers or processes)
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return nil, err
+	}
+	for _, proc := range procs {
+		stat, err := proc.NewStat()
+		if err != nil {
+			level.Info(c.logger).Log("msg", "failed to read proc stat", "err", err)
+			continue
+		}
+
+		cmdLineArgs, err := proc.CmdLine()
+		if err != nil {
+			level.Info(c.logger).Log("msg", "failed to read proc cmdline", "pid", stat.PID, "err", err)
+			continue
+		}
+		cmdLine := strings.Join(cmdLineArgs, " ")
+
+		key, matched := c.matchProcess(cmdLine)
+		if !matched {
+			continue
+		}
+		level.Debug(c.logger).Log("msg", "filtered process", "cmdline", cmdLine, "pid", stat.PID)
+
+		// PPID=1 is a supervisor: only applies to the legacy "/fluentd"
+		// matcher, since a --config.file instance's pid_pattern is the
+		// operator's own say on membership, and containerized fluentd
+		// workers are commonly a direct child of PID 1.
+		if c.instance == nil && stat.PPID == 1 {
+			continue
+		}
+
+		level.Debug(c.logger).Log("msg", "resolved group", "conf_name", key, "pid", stat.PID)
+		if c.target != "" && key != c.target {
+			continue
+		}
+		ids[key] = append(ids[key], stat.PID)
+	}
+	return ids, nil
+}
+
+// matchProcess reports whether the process with the given cmdLine is a
+// fluentd worker process this collector should report on, and the
+// conf_name it belongs to. When a --config.file instance was matched to
+// this collector's target, that instance's pid_pattern decides
+// membership and its name is used as-is; otherwise the legacy
+// "/fluentd" regex and "-c foo.conf" parsing apply. Both match against
+// cmdLine rather than procfs.ProcStat.Comm, since Comm is just the
+// truncated executable name with no arguments.
+func (c *procCollector) matchProcess(cmdLine string) (key string, matched bool) {
+	if c.instance != nil {
+		re := c.instance.PIDRegexp()
+		if re == nil || !re.MatchString(cmdLine) {
+			return "", false
+		}
+		return c.instance.Name, true
+	}
+
+	if !processNameRegex.MatchString(cmdLine) {
+		return "", false
+	}
+
+	groupsKey := configFileNameRegex.FindStringSubmatch(cmdLine)
+	if len(groupsKey) == 0 {
+		return "default", true
+	}
+	return strings.Trim(groupsKey[2], " "), true
+}
+
+func (c *procCollector) procStat(pid int) (procfs.ProcStat, error) {
+	proc, err := c.fs.NewProc(pid)
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to open proc", "pid", pid, "err", err)
+		return procfs.ProcStat{}, err
+	}
+
+	procStat, err := proc.NewStat()
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to read proc stat", "pid", pid, "err", err)
+		return procfs.ProcStat{}, err
+	}
+	return procStat, nil
+}
@@ -0,0 +1,125 @@
+// Package config loads the optional --config.file describing named
+// fluentd instances: how to find their worker processes, where to reach
+// their monitor_agent APIs and file buffer directories, and which static
+// labels to attach to every series collected for them.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Instance describes one fluentd instance to scrape.
+type Instance struct {
+	Name            string            `yaml:"name"`
+	PIDPattern      string            `yaml:"pid_pattern"`
+	MonitorAgentURL string            `yaml:"monitor_agent_url"`
+	BufferPath      string            `yaml:"buffer_path"`
+	Labels          map[string]string `yaml:"labels"`
+
+	pidRegexp *regexp.Regexp
+}
+
+// PIDRegexp returns PIDPattern compiled, or nil if PIDPattern is empty.
+func (i *Instance) PIDRegexp() *regexp.Regexp {
+	return i.pidRegexp
+}
+
+// Config is the document loaded from --config.file.
+type Config struct {
+	Instances []*Instance `yaml:"instances"`
+}
+
+// Instance returns the configured instance named name, if any.
+func (c *Config) Instance(name string) (*Instance, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for _, inst := range c.Instances {
+		if inst.Name == name {
+			return inst, true
+		}
+	}
+	return nil, false
+}
+
+// Load reads and validates the config file at path.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	for _, inst := range cfg.Instances {
+		if inst.Name == "" {
+			return nil, fmt.Errorf("%s: instance missing required name", path)
+		}
+		if inst.PIDPattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(inst.PIDPattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: instance %s: invalid pid_pattern: %w", path, inst.Name, err)
+		}
+		inst.pidRegexp = re
+	}
+	return cfg, nil
+}
+
+// Store holds the currently active Config, swapped atomically on Reload
+// so a scrape that already grabbed a snapshot via Get keeps using it even
+// if a reload happens concurrently.
+type Store struct {
+	path string
+
+	mu  sync.RWMutex
+	cfg *Config
+}
+
+// NewStore loads path into a Store. An empty path is valid and yields a
+// Store whose Get always returns nil, i.e. config-file-less operation.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if path == "" {
+		return s, nil
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the config file from disk and swaps it in.
+func (s *Store) Reload() error {
+	if s.path == "" {
+		return nil
+	}
+	cfg, err := Load(s.path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+	return nil
+}
+
+// Get returns the current Config snapshot, or nil if no config file is
+// in use.
+func (s *Store) Get() *Config {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/promlog"
+	promlogflag "github.com/prometheus/common/promlog/flag"
+	"github.com/prometheus/common/version"
+	"github.com/prometheus/exporter-toolkit/web"
+	"github.com/prometheus/exporter-toolkit/web/kingpinflag"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/gom/fluentd_exporter/collector"
+	"github.com/gom/fluentd_exporter/config"
+)
+
+var (
+	listenAddress = kingpin.Flag("web.listen-address", "Address on which to expose metrics and web interface.").Default(":9224").String()
+	metricsPath   = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
+	configFile    = kingpin.Flag("config.file", "Path to a YAML file describing named fluentd instances: pid_pattern, monitor_agent_url, buffer_path, and static labels. Reloaded on SIGHUP.").Default("").String()
+	webConfigFile = kingpinflag.AddFlags(kingpin.CommandLine)
+)
+
+// probeFailuresTotal is a self-metric, exposed on /metrics, counting
+// requests to /probe that could not even build a scoped collector set.
+var probeFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "fluentd",
+	Name:      "exporter_probe_failures_total",
+	Help:      "Number of /probe requests that failed to build a scoped collector set.",
+})
+
+// probeHandler serves a single-target scrape at /probe?target=<conf_name>
+// (or target=<host:monitor_agent_port>), in the style of blackbox_exporter:
+// a fresh registry and collector set are built for this request only, so
+// one exporter process can be pointed at many fluentd hosts via
+// Prometheus relabeling instead of requiring one exporter per host.
+func probeHandler(cfgStore *config.Store, logger kitlog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		c, err := collector.NewFluentdCollector(target, cfgStore, logger)
+		if err != nil {
+			probeFailuresTotal.Inc()
+			level.Error(logger).Log("msg", "failed to build collectors for target", "target", target, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
+
+func main() {
+	promlogConfig := &promlog.Config{}
+	promlogflag.AddFlags(kingpin.CommandLine, promlogConfig)
+	kingpin.Version(version.Print("fluentd_exporter"))
+	kingpin.HelpFlag.Short('h')
+	kingpin.Parse()
+	logger := promlog.New(promlogConfig)
+
+	cfgStore, err := config.NewStore(*configFile)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to load config file", "path", *configFile, "err", err)
+		os.Exit(1)
+	}
+
+	prometheus.MustRegister(probeFailuresTotal)
+	prometheus.MustRegister(version.NewCollector("fluentd_exporter"))
+
+	level.Info(logger).Log("msg", "Starting fluentd_exporter", "version", version.Info())
+	level.Info(logger).Log("msg", "Build context", "build_context", version.BuildContext())
+
+	mux := http.NewServeMux()
+	mux.Handle(*metricsPath, promhttp.Handler())
+	mux.HandleFunc("/probe", probeHandler(cfgStore, logger))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>
+			<head><title>fluentd Exporter</title></head>
+			<body>
+			<h1>fluentd Exporter v` + version.Info() + `</h1>
+			<p><a href="` + *metricsPath + `">Metrics</a></p>
+			<p><a href="/probe?target=default">Probe the default fluentd instance</a></p>
+			</body>
+		</html>`))
+	})
+
+	srv := &http.Server{Addr: *listenAddress, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- web.ListenAndServe(srv, *webConfigFile, logger)
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	for {
+		select {
+		case err := <-errCh:
+			if err != nil && err != http.ErrServerClosed {
+				level.Error(logger).Log("msg", "error running HTTP server", "err", err)
+				os.Exit(1)
+			}
+			return
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP {
+				level.Info(logger).Log("msg", "reloading config file", "path", *configFile)
+				if err := cfgStore.Reload(); err != nil {
+					level.Error(logger).Log("msg", "failed to reload config file, keeping previous config", "err", err)
+				}
+				continue
+			}
+
+			level.Info(logger).Log("msg", "shutting down, draining in-flight scrapes", "signal", sig)
+			if err := srv.Shutdown(context.Background()); err != nil {
+				level.Error(logger).Log("msg", "error during graceful shutdown", "err", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+}